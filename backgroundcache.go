@@ -0,0 +1,242 @@
+package srvclient
+
+// This file implements EnableBackgroundCache, a TTL-aware alternative to
+// EnableCacheLast. Where cacheLast only ever refills on a successful lookup,
+// this proactively refreshes entries before they go stale and keeps serving
+// a stale answer for a grace period if refreshes start failing, the same
+// "stale-while-error" behavior DNS-based service discovery layers like
+// Thanos's provide.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// defaultBackgroundCacheGrace is used in place of
+// SRVClient.BackgroundCacheGraceWindow when it's left at its zero value
+const defaultBackgroundCacheGrace = 5 * time.Minute
+
+// backgroundCacheRefreshInterval is how often the background goroutine scans
+// for entries that are due for a refresh
+const backgroundCacheRefreshInterval = time.Second
+
+// BackgroundCacheStats is a snapshot of EnableBackgroundCache's counters,
+// suitable for wiring into a caller's own metrics
+type BackgroundCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	StaleServes   uint64
+	RefreshErrors uint64
+}
+
+// bgCacheEntry holds the cached response for a single hostname
+type bgCacheEntry struct {
+	hostname string
+
+	mu      sync.Mutex
+	res     *dns.Msg
+	expires time.Time // when the cached answer's TTL runs out
+	staleAt time.Time // set once a refresh fails; zero means not stale
+}
+
+// bgCache is the TTL-aware cache backing EnableBackgroundCache
+type bgCache struct {
+	sc            *SRVClient
+	refreshJitter time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]*bgCacheEntry
+
+	statsMu sync.Mutex
+	stats   BackgroundCacheStats
+}
+
+// EnableBackgroundCache opts sc into a TTL-aware cache: lookups are served
+// out of an in-memory cache keyed by hostname, which a single background
+// goroutine keeps warm by re-querying each entry shortly before its SRV RRset
+// (and any glue A/AAAA records) would expire. refreshJitter is subtracted
+// from an entry's expiry to decide when that early refresh happens. The same
+// fixed lead applies to every entry, so entries that expire together are
+// refreshed in the same scan.
+//
+// If a refresh fails, the stale entry keeps being served for
+// BackgroundCacheGraceWindow (5 minutes by default) before being evicted.
+// Calling EnableBackgroundCache more than once has no effect after the first
+// call
+func (sc *SRVClient) EnableBackgroundCache(refreshJitter time.Duration) {
+	sc.bgCacheL.Lock()
+	defer sc.bgCacheL.Unlock()
+	if sc.bgCache != nil {
+		return
+	}
+
+	c := &bgCache{
+		sc:            sc,
+		refreshJitter: refreshJitter,
+		entries:       map[string]*bgCacheEntry{},
+	}
+	sc.bgCache = c
+	go c.run()
+}
+
+// BackgroundCacheStats returns a snapshot of the background cache's counters.
+// It returns the zero value if EnableBackgroundCache hasn't been called
+func (sc *SRVClient) BackgroundCacheStats() BackgroundCacheStats {
+	sc.bgCacheL.Lock()
+	c := sc.bgCache
+	sc.bgCacheL.Unlock()
+	if c == nil {
+		return BackgroundCacheStats{}
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.stats
+}
+
+// lookup serves hostname out of the cache, populating it on a miss.
+// forceSecure, as in lookupSRVContext, requires the served response to be
+// authenticated for this call even if c.sc.Secure isn't set - a cached
+// response is shared across every caller regardless of the secure-ness of
+// the call that originally populated it, so it's re-checked here rather than
+// only at populate time
+func (c *bgCache) lookup(ctx context.Context, hostname string, forceSecure bool) (*dns.Msg, error) {
+	secure := c.sc.Secure || forceSecure
+
+	c.mu.RLock()
+	e, ok := c.entries[hostname]
+	c.mu.RUnlock()
+
+	if ok {
+		e.mu.Lock()
+		res, stale := e.res, !e.staleAt.IsZero()
+		e.mu.Unlock()
+
+		if err := checkSecure(res, hostname, secure, c.sc.ValidateDNSSEC, c.sc.TrustAnchor); err != nil {
+			return nil, err
+		}
+
+		c.statsMu.Lock()
+		c.stats.Hits++
+		if stale {
+			c.stats.StaleServes++
+		}
+		c.statsMu.Unlock()
+
+		return res, nil
+	}
+
+	c.statsMu.Lock()
+	c.stats.Misses++
+	c.statsMu.Unlock()
+
+	res, err := c.sc.exchangeHostname(ctx, hostname, forceSecure)
+	if err != nil || res == nil {
+		return res, err
+	}
+
+	c.mu.Lock()
+	c.entries[hostname] = &bgCacheEntry{
+		hostname: hostname,
+		res:      res,
+		expires:  time.Now().Add(minTTL(res)),
+	}
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+// run periodically refreshes entries that are nearing their TTL
+func (c *bgCache) run() {
+	ticker := time.NewTicker(backgroundCacheRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.refreshDue()
+	}
+}
+
+func (c *bgCache) refreshDue() {
+	now := time.Now()
+
+	c.mu.RLock()
+	var due []*bgCacheEntry
+	for _, e := range c.entries {
+		e.mu.Lock()
+		if now.After(e.expires.Add(-c.refreshJitter)) {
+			due = append(due, e)
+		}
+		e.mu.Unlock()
+	}
+	c.mu.RUnlock()
+
+	for _, e := range due {
+		c.refresh(e)
+	}
+}
+
+func (c *bgCache) refresh(e *bgCacheEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := c.sc.exchangeHostname(ctx, e.hostname, false)
+
+	if err != nil || res == nil {
+		c.statsMu.Lock()
+		c.stats.RefreshErrors++
+		c.statsMu.Unlock()
+
+		grace := c.sc.BackgroundCacheGraceWindow
+		if grace == 0 {
+			grace = defaultBackgroundCacheGrace
+		}
+
+		e.mu.Lock()
+		if e.staleAt.IsZero() {
+			e.staleAt = time.Now().Add(grace)
+		}
+		evict := time.Now().After(e.staleAt)
+		e.mu.Unlock()
+
+		if evict {
+			c.mu.Lock()
+			delete(c.entries, e.hostname)
+			c.mu.Unlock()
+		}
+		return
+	}
+
+	e.mu.Lock()
+	e.res = res
+	e.expires = time.Now().Add(minTTL(res))
+	e.staleAt = time.Time{}
+	e.mu.Unlock()
+}
+
+// minTTL returns the lowest TTL among an SRV response's answer and extra
+// (glue A/AAAA) records, falling back to 60s if the response carried none
+func minTTL(res *dns.Msg) time.Duration {
+	var min uint32
+	var found bool
+	consider := func(ttl uint32) {
+		if !found || ttl < min {
+			min = ttl
+			found = true
+		}
+	}
+	for _, rr := range res.Answer {
+		consider(rr.Header().Ttl)
+	}
+	for _, rr := range res.Extra {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA:
+			consider(rr.Header().Ttl)
+		}
+	}
+	if !found {
+		min = 60
+	}
+	return time.Duration(min) * time.Second
+}
@@ -0,0 +1,141 @@
+package srvclient
+
+// This file is responsible for reading and periodically refreshing the
+// system's /etc/resolv.conf so that lookupSRV can pick servers, search
+// domains, and other resolver options the same way the standard library's
+// resolver does. It's kept in sync with it manually since those bits aren't
+// exported from the net package.
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	resolvConfPath        = "/etc/resolv.conf"
+	dnsConfigReloadPeriod = 5 * time.Second
+)
+
+// dnsConfig holds the parts of /etc/resolv.conf that this package cares
+// about
+type dnsConfig struct {
+	servers  []string // server addresses (in "host" or "host:port" form)
+	search   []string // rooted search domains, in resolv.conf order
+	ndots    int      // number of dots in name to trigger absolute lookup
+	timeout  int      // seconds before giving up on a query, 0 means default
+	attempts int      // lost packets before giving up on server
+}
+
+var (
+	dnsConfigL   sync.RWMutex
+	dnsConfigCur *dnsConfig
+	dnsConfigErr error
+)
+
+// dnsGetConfig returns the most recently loaded dnsConfig, reading it for the
+// first time if dnsConfigLoop hasn't populated it yet
+func dnsGetConfig() (*dnsConfig, error) {
+	dnsConfigL.RLock()
+	cfg, err := dnsConfigCur, dnsConfigErr
+	dnsConfigL.RUnlock()
+	if cfg != nil || err != nil {
+		return cfg, err
+	}
+
+	cfg, err = dnsReadConfig(resolvConfPath)
+	dnsConfigL.Lock()
+	dnsConfigCur, dnsConfigErr = cfg, err
+	dnsConfigL.Unlock()
+	return cfg, err
+}
+
+// dnsConfigLoop periodically re-reads resolvConfPath so that changes (e.g. a
+// DNS server being swapped out from under a long-running process) get
+// picked up without a restart
+func dnsConfigLoop() {
+	for {
+		time.Sleep(dnsConfigReloadPeriod)
+		cfg, err := dnsReadConfig(resolvConfPath)
+		dnsConfigL.Lock()
+		dnsConfigCur, dnsConfigErr = cfg, err
+		dnsConfigL.Unlock()
+	}
+}
+
+// dnsReadConfig parses a resolv.conf style file, following the same rules as
+// resolv.conf(5): "nameserver", "search"/"domain", and "options
+// ndots:n"/"timeout:n"/"attempts:n" are recognized, the last "search" or
+// "domain" directive wins, and unknown lines are ignored
+func dnsReadConfig(path string) (*dnsConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg := &dnsConfig{
+		ndots:    1,
+		timeout:  5,
+		attempts: 2,
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "nameserver":
+			if len(fields) > 1 {
+				cfg.servers = append(cfg.servers, fields[1])
+			}
+		case "domain":
+			if len(fields) > 1 {
+				cfg.search = []string{dns.Fqdn(fields[1])}
+			}
+		case "search":
+			cfg.search = cfg.search[:0]
+			for _, s := range fields[1:] {
+				cfg.search = append(cfg.search, dns.Fqdn(s))
+			}
+		case "options":
+			for _, s := range fields[1:] {
+				switch {
+				case strings.HasPrefix(s, "ndots:"):
+					if n, err := strconv.Atoi(s[len("ndots:"):]); err == nil {
+						cfg.ndots = n
+					}
+				case strings.HasPrefix(s, "timeout:"):
+					if n, err := strconv.Atoi(s[len("timeout:"):]); err == nil {
+						cfg.timeout = n
+					}
+				case strings.HasPrefix(s, "attempts:"):
+					if n, err := strconv.Atoi(s[len("attempts:"):]); err == nil {
+						cfg.attempts = n
+					}
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.servers) == 0 {
+		cfg.servers = []string{"127.0.0.1"}
+	}
+
+	return cfg, nil
+}
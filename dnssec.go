@@ -0,0 +1,99 @@
+package srvclient
+
+// This file backs SRVClient.Secure: enforcing that a SRV response came back
+// authenticated (or, with ValidateDNSSEC, independently verifying it) so that
+// security-sensitive service discovery (Kerberos KDC location, XMPP
+// federation, etc) can refuse to connect to unauthenticated targets.
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// ErrInsecureResponse is returned by a Secure lookup (or SRVSecure/
+// AllSRVSecure) when the response either lacks the AD (Authenticated Data)
+// bit or fails local validation under ValidateDNSSEC
+type ErrInsecureResponse struct {
+	Hostname string
+	Reason   string
+}
+
+func (e *ErrInsecureResponse) Error() string {
+	return fmt.Sprintf("insecure SRV response for %q: %s", e.Hostname, e.Reason)
+}
+
+// checkSecure enforces secure (sc.Secure, or a one-off override from
+// SRVSecureContext/AllSRVSecureContext) against res, returning
+// ErrInsecureResponse if it doesn't hold up. It's a free function rather than
+// a method so that SRVClient's fields don't need to be read through a locked
+// (and possibly copied) receiver just to check them. It's a no-op if secure
+// is false
+func checkSecure(res *dns.Msg, hostname string, secure, validate bool, anchor *dns.DNSKEY) error {
+	if !secure {
+		return nil
+	}
+
+	if !res.AuthenticatedData {
+		return &ErrInsecureResponse{Hostname: hostname, Reason: "response is missing the AD (Authenticated Data) bit"}
+	}
+
+	if validate {
+		if anchor == nil {
+			return &ErrInsecureResponse{Hostname: hostname, Reason: "ValidateDNSSEC is set but no TrustAnchor is configured"}
+		}
+		if err := validateRRSIG(res, anchor); err != nil {
+			return &ErrInsecureResponse{Hostname: hostname, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// validateRRSIG verifies that the SRV RRset in res.Answer is covered by an
+// RRSIG which validates against anchor. Deliberately, only anchor is trusted
+// here - DNSKEY records the response itself carries in res.Extra are NOT
+// considered, since a hostile or compromised resolver could stuff its own
+// forged key material in there and have it "validate" against itself. This
+// only validates the single RRSIG/anchor pair - it does not walk the
+// delegation chain up to a root of trust. That means anchor must be the
+// DNSKEY that actually signs the zone's SRV RRset (its ZSK, in most setups) -
+// the IANA root KSK will never satisfy this check, since its key tag can't
+// match a leaf zone's RRSIG. Callers wanting a real chain of trust up to the
+// root need to fetch and verify the DS/DNSKEY chain themselves and pass the
+// zone's own validated DNSKEY as anchor
+func validateRRSIG(res *dns.Msg, anchor *dns.DNSKEY) error {
+	if anchor == nil {
+		return errors.New("no TrustAnchor configured to validate the RRSIG against")
+	}
+
+	var srvSet []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range res.Answer {
+		switch v := rr.(type) {
+		case *dns.SRV:
+			srvSet = append(srvSet, rr)
+		case *dns.RRSIG:
+			if v.TypeCovered == dns.TypeSRV {
+				sigs = append(sigs, v)
+			}
+		}
+	}
+	if len(sigs) == 0 {
+		return errors.New("no RRSIG covering the SRV RRset")
+	}
+	if len(srvSet) == 0 {
+		return errors.New("no SRV records to validate")
+	}
+
+	for _, sig := range sigs {
+		if anchor.KeyTag() != sig.KeyTag {
+			continue
+		}
+		if err := sig.Verify(anchor, srvSet); err == nil {
+			return nil
+		}
+	}
+	return errors.New("no DNSKEY validated the SRV RRset's RRSIG")
+}
@@ -5,6 +5,7 @@ package srvclient
 // having to manually parse /etc/resolv.conf and manually make the SRV requests.
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -19,17 +20,61 @@ import (
 	"github.com/miekg/dns"
 )
 
-// sortableSRV implements sort.Interface for []*dns.SRV based on
-// the Priority and Weight fields
-type sortableSRV []*dns.SRV
+// orderSRV returns ans ordered per RFC 2782: grouped by ascending priority,
+// and within each priority group weighted-randomly shuffled using the
+// running-sum selection algorithm (pick one weighted-random target, remove
+// it, repeat). The result is a valid connect-attempt order, not just a sort
+func orderSRV(ans []*dns.SRV) []*dns.SRV {
+	groups := map[uint16][]*dns.SRV{}
+	var prios []int
+	for _, s := range ans {
+		if _, ok := groups[s.Priority]; !ok {
+			prios = append(prios, int(s.Priority))
+		}
+		groups[s.Priority] = append(groups[s.Priority], s)
+	}
+	sort.Ints(prios)
 
-func (a sortableSRV) Len() int      { return len(a) }
-func (a sortableSRV) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
-func (a sortableSRV) Less(i, j int) bool {
-	if a[i].Priority == a[j].Priority {
-		return a[i].Weight > a[j].Weight
+	randSrc := rand.New(rand.NewSource(time.Now().UnixNano()))
+	res := make([]*dns.SRV, 0, len(ans))
+	for _, p := range prios {
+		res = append(res, weightedShuffle(groups[uint16(p)], randSrc)...)
 	}
-	return a[i].Priority < a[j].Priority
+	return res
+}
+
+// weightedShuffle repeatedly picks a weighted-random entry from srvs (per the
+// RFC 2782 running-sum algorithm), removes it, and appends it to the result,
+// producing a full ordering rather than a single pick
+func weightedShuffle(srvs []*dns.SRV, r *rand.Rand) []*dns.SRV {
+	remaining := append([]*dns.SRV(nil), srvs...)
+	res := make([]*dns.SRV, 0, len(remaining))
+
+	for len(remaining) > 0 {
+		sum := 0
+		for _, s := range remaining {
+			sum += int(s.Weight)
+		}
+
+		idx := 0
+		if sum == 0 {
+			idx = r.Intn(len(remaining))
+		} else {
+			pick := r.Intn(sum)
+			for i, s := range remaining {
+				pick -= int(s.Weight)
+				if pick < 0 {
+					idx = i
+					break
+				}
+			}
+		}
+
+		res = append(res, remaining[idx])
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+	}
+
+	return res
 }
 
 func init() {
@@ -44,6 +89,84 @@ type SRVClient struct {
 	// Defaults to the global getCFGServers if not specified. Nice to have here
 	// for tests
 	getCFGServers func(*dnsConfig) []string
+
+	// DisableTCPFallback prevents lookupSRV from retrying over TCP when a UDP
+	// response comes back with the Truncated bit set. Callers who want strict
+	// UDP-only behavior (e.g. to avoid the extra round-trip) can set this
+	DisableTCPFallback bool
+
+	// Exchange, if set, is used to perform the actual SRV query instead of
+	// constructing a dns.Client and talking UDP/TCP to server directly. This
+	// makes it possible to unit-test lookupSRV against a fake resolver, or to
+	// route lookups over an alternate transport (DoT/DoH, a Unix socket, an
+	// in-process resolver). See ExchangeWithDial for a ready-made adapter
+	Exchange ExchangeFunc
+
+	// BackgroundCacheGraceWindow controls how long EnableBackgroundCache keeps
+	// serving a stale cached answer after a refresh attempt fails, before
+	// evicting it. Defaults to 5 minutes if zero
+	BackgroundCacheGraceWindow time.Duration
+
+	bgCache  *bgCache
+	bgCacheL sync.Mutex
+
+	// Secure, when true, sets the DNSSEC OK (DO) bit on outgoing queries and
+	// requires the Authenticated Data (AD) bit on the response. A response
+	// lacking AD (or failing local validation, see ValidateDNSSEC) causes the
+	// lookup to fail with ErrInsecureResponse instead of returning records.
+	// SRVSecure/AllSRVSecure are shorthand for a one-off secure lookup without
+	// having to set this on the client itself
+	Secure bool
+
+	// ValidateDNSSEC, when true (and Secure is set), additionally validates
+	// the RRSIG covering the SRV RRset against TrustAnchor rather than
+	// trusting the upstream resolver's AD bit alone. DNSKEY records carried in
+	// the response are not used, and there is no default anchor (see
+	// TrustAnchor). This matters for callers who don't trust their configured
+	// resolver to have validated correctly
+	ValidateDNSSEC bool
+
+	// TrustAnchor is the DNSKEY that ValidateDNSSEC validates the SRV
+	// response's RRSIG against. It is required when ValidateDNSSEC is set -
+	// lookups fail closed with ErrInsecureResponse if it's left nil, rather
+	// than trusting any DNSKEY the (possibly hostile) response happens to
+	// carry. There is no built-in delegation-chain walk, so this must be the
+	// zone's own DNSKEY that actually signs its SRV RRset (its ZSK, in most
+	// setups), not the IANA root KSK - the root key's tag will never match a
+	// leaf zone's RRSIG. Callers needing a full chain of trust up to the root
+	// must validate the DS/DNSKEY chain themselves and supply the zone's
+	// validated DNSKEY here
+	TrustAnchor *dns.DNSKEY
+}
+
+// ExchangeFunc performs a single DNS exchange of msg against server, in the
+// same vein as dns.Client.Exchange but pluggable
+type ExchangeFunc func(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error)
+
+// DialFunc mirrors the shape of net.Resolver's Dial field, so the same dialer
+// used to fake out net.Resolver in tests (or to route it over an alternate
+// transport) can be reused here via ExchangeWithDial
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// ExchangeWithDial builds an ExchangeFunc that performs the DNS exchange over
+// a connection obtained from dial rather than dialing UDP/TCP directly. This
+// mirrors the ResolverDialFunc pattern the Go standard library uses for
+// net.Resolver, and lets callers inject fake connections in tests or run DNS
+// over a custom transport
+func ExchangeWithDial(dial DialFunc) ExchangeFunc {
+	return func(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+		conn, err := dial(ctx, "udp", server)
+		if err != nil {
+			return nil, err
+		}
+		dc := &dns.Conn{Conn: conn}
+		defer dc.Close()
+
+		if err := dc.WriteMsg(msg); err != nil {
+			return nil, err
+		}
+		return dc.ReadMsg()
+	}
 }
 
 // When used, SRVClient will cache the last successful SRV response for each
@@ -86,7 +209,7 @@ func getCFGServers(cfg *dnsConfig) []string {
 	return res
 }
 
-func (sc SRVClient) doCacheLast(hostname string, res *dns.Msg) *dns.Msg {
+func (sc *SRVClient) doCacheLast(hostname string, res *dns.Msg) *dns.Msg {
 	if sc.cacheLast == nil {
 		return res
 	}
@@ -103,7 +226,104 @@ func (sc SRVClient) doCacheLast(hostname string, res *dns.Msg) *dns.Msg {
 	return res
 }
 
-func (sc SRVClient) lookupSRV(hostname string, replaceWithIPs bool) ([]*dns.SRV, error) {
+// maybeRetryTCP re-issues msg over TCP against server when res came back with
+// the Truncated bit set, as UDP responses do when they don't fit in the
+// negotiated message size. It returns res unmodified if no retry was needed.
+// DisableTCPFallback opts out of this behavior entirely. If sc.Exchange is
+// set, the caller owns the transport, so no TCP retry is attempted here
+func (sc *SRVClient) maybeRetryTCP(ctx context.Context, c *dns.Client, res *dns.Msg, msg *dns.Msg, server string) (*dns.Msg, error) {
+	if !res.Truncated || sc.DisableTCPFallback || sc.Exchange != nil {
+		return res, nil
+	}
+
+	tc := *c
+	tc.Net = "tcp"
+	tcpRes, _, err := tc.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+	return tcpRes, nil
+}
+
+// exchange performs msg against server, using sc.Exchange if set, falling
+// back to c otherwise
+func (sc *SRVClient) exchange(ctx context.Context, c *dns.Client, msg *dns.Msg, server string) (*dns.Msg, error) {
+	if sc.Exchange != nil {
+		return sc.Exchange(ctx, msg, server)
+	}
+	res, _, err := c.ExchangeContext(ctx, msg, server)
+	return res, err
+}
+
+// searchFqdns returns the ordered list of fully-qualified names that should
+// be tried for hostname. If hostname is already absolute (ends in a dot) or
+// has at least cfg.ndots dots in it, only its FQDN form is tried. Otherwise
+// each of cfg.search's suffixes is tried first, with the bare FQDN tried last
+// as a fallback, mirroring the resolv.conf(5) search/ndots behavior the
+// standard library's resolver implements
+func searchFqdns(hostname string, cfg *dnsConfig) []string {
+	fqdn := dns.Fqdn(hostname)
+	if strings.HasSuffix(hostname, ".") || len(cfg.search) == 0 {
+		return []string{fqdn}
+	}
+	if strings.Count(hostname, ".") >= cfg.ndots {
+		return []string{fqdn}
+	}
+
+	names := make([]string, 0, len(cfg.search)+1)
+	for _, suffix := range cfg.search {
+		names = append(names, dns.Fqdn(hostname)+suffix)
+	}
+	names = append(names, fqdn)
+	return names
+}
+
+// exchangeFqdn performs the actual SRV exchange for a single fully-qualified
+// name, trying each server in turn and falling back to a non-EDNS0 query on
+// RcodeFormatError, with an optional TCP retry on truncation. secure sets the
+// DNSSEC OK (DO) bit on the outgoing query
+func (sc *SRVClient) exchangeFqdn(ctx context.Context, c *dns.Client, servers []string, fqdn string, secure bool) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(fqdn, dns.TypeSRV)
+	m.SetEdns0(dns.DefaultMsgSize, secure)
+
+	var res *dns.Msg
+	var err error
+	for _, server := range servers {
+		if res, err = sc.exchange(ctx, c, m, server); err != nil {
+			continue
+		}
+		if res.Rcode != dns.RcodeFormatError {
+			if res, err = sc.maybeRetryTCP(ctx, c, res, m, server); err != nil {
+				continue
+			}
+			return res, nil
+		}
+
+		// At this point we got a response, but it was just to tell us that
+		// edns0 isn't supported, so we try again without it
+		m2 := new(dns.Msg)
+		m2.SetQuestion(fqdn, dns.TypeSRV)
+		if res, err = sc.exchange(ctx, c, m2, server); err == nil {
+			if res, err = sc.maybeRetryTCP(ctx, c, res, m2, server); err != nil {
+				continue
+			}
+			return res, nil
+		}
+	}
+	return nil, err
+}
+
+// exchangeHostname performs the raw SRV exchange for hostname, trying each of
+// its search-list expansions in turn, and returns the first NOERROR response
+// with a non-empty answer. If no search name yields one, it returns the last
+// response received (e.g. an NXDOMAIN), or a nil *dns.Msg (with a nil error)
+// if every exchange failed without a response. forceSecure, in addition to
+// sc.Secure, requires the response to come back authenticated; it's how
+// SRVSecureContext/AllSRVSecureContext get secure semantics for a single call
+// without mutating sc itself
+func (sc *SRVClient) exchangeHostname(ctx context.Context, hostname string, forceSecure bool) (*dns.Msg, error) {
+	secure := sc.Secure || forceSecure
 	cfg, err := dnsGetConfig()
 	if err != nil {
 		return nil, err
@@ -117,37 +337,67 @@ func (sc SRVClient) lookupSRV(hostname string, replaceWithIPs bool) ([]*dns.SRV,
 		c.ReadTimeout = timeout
 		c.WriteTimeout = timeout
 	}
-	fqdn := dns.Fqdn(hostname)
-	m := new(dns.Msg)
-	m.SetQuestion(fqdn, dns.TypeSRV)
-	m.SetEdns0(dns.DefaultMsgSize, false)
 
-	var res *dns.Msg
 	getCFGFn := sc.getCFGServers
 	if getCFGFn == nil {
 		getCFGFn = getCFGServers
 	}
 	servers := getCFGFn(cfg)
-	for _, server := range servers {
-		if res, _, err = c.Exchange(m, server); err != nil {
+
+	// lastRes keeps the most recent non-erroring response around so that, if
+	// none of the search names produces a non-empty NOERROR answer, we still
+	// return something for the caller to see a proper "no SRV records" error
+	// against (rather than a generic "no available nameservers")
+	var lastRes *dns.Msg
+	for _, fqdn := range searchFqdns(hostname, cfg) {
+		res, err := sc.exchangeFqdn(ctx, c, servers, fqdn, secure)
+		if err != nil {
 			continue
 		}
-		if res.Rcode != dns.RcodeFormatError {
-			break
+		lastRes = res
+		if res.Rcode == dns.RcodeSuccess && len(res.Answer) > 0 {
+			if err := checkSecure(res, hostname, secure, sc.ValidateDNSSEC, sc.TrustAnchor); err != nil {
+				return nil, err
+			}
+			return res, nil
 		}
+		// NXDOMAIN, NODATA, or a server failure for this search name, try the
+		// next one
+	}
 
-		// At this point we got a response, but it was just to tell us that
-		// edns0 isn't supported, so we try again without it
-		m2 := new(dns.Msg)
-		m2.SetQuestion(fqdn, dns.TypeSRV)
-		if res, _, err = c.Exchange(m2, server); err == nil {
-			break
+	if lastRes != nil {
+		if err := checkSecure(lastRes, hostname, secure, sc.ValidateDNSSEC, sc.TrustAnchor); err != nil {
+			return nil, err
 		}
 	}
+	return lastRes, nil
+}
+
+func (sc *SRVClient) lookupSRV(hostname string, replaceWithIPs bool) ([]*dns.SRV, error) {
+	return sc.lookupSRVContext(context.Background(), hostname, replaceWithIPs, false)
+}
+
+func (sc *SRVClient) lookupSRVContext(ctx context.Context, hostname string, replaceWithIPs, forceSecure bool) ([]*dns.SRV, error) {
+	var res *dns.Msg
+	var err error
+
+	sc.bgCacheL.Lock()
+	bc := sc.bgCache
+	sc.bgCacheL.Unlock()
 
-	// Handles caching this response if it's a successful one, or replacing res
-	// with the last response if not. Does nothing if sc.cacheLast is false.
-	res = sc.doCacheLast(hostname, res)
+	if bc != nil {
+		if res, err = bc.lookup(ctx, hostname, forceSecure); err != nil {
+			return nil, err
+		}
+	} else {
+		if res, err = sc.exchangeHostname(ctx, hostname, forceSecure); err != nil {
+			return nil, err
+		}
+
+		// Handles caching this response if it's a successful one, or replacing
+		// res with the last response if not. Does nothing if sc.cacheLast is nil.
+		res = sc.doCacheLast(hostname, res)
+	}
 
 	if res == nil {
 		return nil, errors.New("no available nameservers")
@@ -191,14 +441,33 @@ func SRV(hostname string) (string, error) {
 //
 // If the given hostname already has a ":port" appended to it, only the ip will
 // be looked up from the SRV request, but the port given will be returned
-func (sc SRVClient) SRV(hostname string) (string, error) {
+func (sc *SRVClient) SRV(hostname string) (string, error) {
+	return sc.SRVContext(context.Background(), hostname)
+}
+
+// SRVContext calls the SRVContext method on the DefaultSRVClient
+func SRVContext(ctx context.Context, hostname string) (string, error) {
+	return DefaultSRVClient.SRVContext(ctx, hostname)
+}
+
+// SRVContext behaves the same as SRV, but the given Context is threaded
+// through to the underlying DNS exchange so that callers can cancel an
+// in-flight lookup or impose a per-call deadline
+func (sc *SRVClient) SRVContext(ctx context.Context, hostname string) (string, error) {
+	return sc.srvContext(ctx, hostname, false)
+}
+
+// srvContext is the shared implementation behind SRVContext and
+// SRVSecureContext. forceSecure requires secure semantics for this one call,
+// without setting sc.Secure (and so without affecting any other caller of sc)
+func (sc *SRVClient) srvContext(ctx context.Context, hostname string, forceSecure bool) (string, error) {
 	var portStr string
 	if parts := strings.Split(hostname, ":"); len(parts) == 2 {
 		hostname = parts[0]
 		portStr = parts[1]
 	}
 
-	ans, err := sc.lookupSRV(hostname, true)
+	ans, err := sc.lookupSRVContext(ctx, hostname, true, forceSecure)
 	if err != nil {
 		return "", err
 	}
@@ -208,6 +477,29 @@ func (sc SRVClient) SRV(hostname string) (string, error) {
 	return srvToStr(srv, portStr), nil
 }
 
+// SRVSecure calls the SRVSecure method on the DefaultSRVClient
+func SRVSecure(hostname string) (string, error) {
+	return DefaultSRVClient.SRVSecure(hostname)
+}
+
+// SRVSecure behaves the same as SRV, but as if Secure were set: the DO bit is
+// set on the outgoing query, and a response lacking the AD bit (or failing
+// local validation, if ValidateDNSSEC is also set) returns ErrInsecureResponse
+func (sc *SRVClient) SRVSecure(hostname string) (string, error) {
+	return sc.SRVSecureContext(context.Background(), hostname)
+}
+
+// SRVSecureContext calls the SRVSecureContext method on the DefaultSRVClient
+func SRVSecureContext(ctx context.Context, hostname string) (string, error) {
+	return DefaultSRVClient.SRVSecureContext(ctx, hostname)
+}
+
+// SRVSecureContext behaves the same as SRVSecure, but the given Context is
+// threaded through to the underlying DNS exchange
+func (sc *SRVClient) SRVSecureContext(ctx context.Context, hostname string) (string, error) {
+	return sc.srvContext(ctx, hostname, true)
+}
+
 // SRVNoPort calls the SRVNoPort method on the DefaultSRVClient
 func SRVNoPort(hostname string) (string, error) {
 	return DefaultSRVClient.SRVNoPort(hostname)
@@ -215,8 +507,19 @@ func SRVNoPort(hostname string) (string, error) {
 
 // SRVNoPort behaves the same as SRV, but the returned address string will not
 // contain the port
-func (sc SRVClient) SRVNoPort(hostname string) (string, error) {
-	addr, err := SRV(hostname)
+func (sc *SRVClient) SRVNoPort(hostname string) (string, error) {
+	return sc.SRVNoPortContext(context.Background(), hostname)
+}
+
+// SRVNoPortContext calls the SRVNoPortContext method on the DefaultSRVClient
+func SRVNoPortContext(ctx context.Context, hostname string) (string, error) {
+	return DefaultSRVClient.SRVNoPortContext(ctx, hostname)
+}
+
+// SRVNoPortContext behaves the same as SRVNoPort, but the given Context is
+// threaded through to the underlying DNS exchange
+func (sc *SRVClient) SRVNoPortContext(ctx context.Context, hostname string) (string, error) {
+	addr, err := sc.SRVContext(ctx, hostname)
 	if err != nil {
 		return "", err
 	}
@@ -230,24 +533,45 @@ func AllSRV(hostname string) ([]string, error) {
 	return DefaultSRVClient.AllSRV(hostname)
 }
 
-// AllSRV returns the list of all hostnames and ports for the SRV lookup
-// The results are sorted by priority and then weight. Like SRV, if hostname
-// contained a port then the port on all results will be replaced with the
-// originally-passed port
+// AllSRV returns the list of all hostnames and ports for the SRV lookup,
+// ordered per RFC 2782: grouped by ascending priority, with each priority
+// group weighted-randomly shuffled, so the result is a valid connect-attempt
+// order (see Dial, which walks it for automatic failover). Like SRV, if
+// hostname contained a port then the port on all results will be replaced
+// with the originally-passed port
 // AllSRV will NOT replace hostnames with their respective IPs
-func (sc SRVClient) AllSRV(hostname string) ([]string, error) {
+func (sc *SRVClient) AllSRV(hostname string) ([]string, error) {
+	return sc.AllSRVContext(context.Background(), hostname)
+}
+
+// AllSRVContext calls the AllSRVContext method on the DefaultSRVClient
+func AllSRVContext(ctx context.Context, hostname string) ([]string, error) {
+	return DefaultSRVClient.AllSRVContext(ctx, hostname)
+}
+
+// AllSRVContext behaves the same as AllSRV, but the given Context is threaded
+// through to the underlying DNS exchange
+func (sc *SRVClient) AllSRVContext(ctx context.Context, hostname string) ([]string, error) {
+	return sc.allSRVContext(ctx, hostname, false)
+}
+
+// allSRVContext is the shared implementation behind AllSRVContext and
+// AllSRVSecureContext. forceSecure requires secure semantics for this one
+// call, without setting sc.Secure (and so without affecting any other caller
+// of sc)
+func (sc *SRVClient) allSRVContext(ctx context.Context, hostname string, forceSecure bool) ([]string, error) {
 	var ogPort string
 	if parts := strings.Split(hostname, ":"); len(parts) == 2 {
 		hostname = parts[0]
 		ogPort = parts[1]
 	}
 
-	ans, err := sc.lookupSRV(hostname, false)
+	ans, err := sc.lookupSRVContext(ctx, hostname, false, forceSecure)
 	if err != nil {
 		return nil, err
 	}
 
-	sort.Sort(sortableSRV(ans))
+	ans = orderSRV(ans)
 
 	res := make([]string, len(ans))
 	for i := range ans {
@@ -256,6 +580,61 @@ func (sc SRVClient) AllSRV(hostname string) ([]string, error) {
 	return res, nil
 }
 
+// AllSRVSecure calls the AllSRVSecure method on the DefaultSRVClient
+func AllSRVSecure(hostname string) ([]string, error) {
+	return DefaultSRVClient.AllSRVSecure(hostname)
+}
+
+// AllSRVSecure behaves the same as AllSRV, but as if Secure were set: the DO
+// bit is set on the outgoing query, and a response lacking the AD bit (or
+// failing local validation, if ValidateDNSSEC is also set) returns
+// ErrInsecureResponse
+func (sc *SRVClient) AllSRVSecure(hostname string) ([]string, error) {
+	return sc.AllSRVSecureContext(context.Background(), hostname)
+}
+
+// AllSRVSecureContext calls the AllSRVSecureContext method on the
+// DefaultSRVClient
+func AllSRVSecureContext(ctx context.Context, hostname string) ([]string, error) {
+	return DefaultSRVClient.AllSRVSecureContext(ctx, hostname)
+}
+
+// AllSRVSecureContext behaves the same as AllSRVSecure, but the given Context
+// is threaded through to the underlying DNS exchange
+func (sc *SRVClient) AllSRVSecureContext(ctx context.Context, hostname string) ([]string, error) {
+	return sc.allSRVContext(ctx, hostname, true)
+}
+
+// Dial calls the Dial method on the DefaultSRVClient
+func Dial(ctx context.Context, network, hostname string) (net.Conn, error) {
+	return DefaultSRVClient.Dial(ctx, network, hostname)
+}
+
+// Dial performs a SRV lookup on hostname via AllSRVContext and dials each
+// returned target in turn, in RFC 2782 order, until one succeeds. This gives
+// callers automatic failover across both weight and priority tiers without
+// having to reimplement the connect-attempt loop themselves. It returns the
+// first successful connection, or the last dial error seen if every target
+// failed
+func (sc *SRVClient) Dial(ctx context.Context, network, hostname string) (net.Conn, error) {
+	addrs, err := sc.AllSRVContext(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return conn, nil
+	}
+	return nil, lastErr
+}
+
 // MaybeSRV calls the MaybeSRV method on the DefaultSRVClient
 func MaybeSRV(host string) string {
 	return DefaultSRVClient.MaybeSRV(host)
@@ -264,15 +643,32 @@ func MaybeSRV(host string) string {
 // MaybeSRV attempts a SRV lookup if the host doesn't contain a port and if the
 // SRV lookup succeeds it'll rewrite the host and return it with the lookup
 // result. If it fails it'll just return the host originally sent
-func (sc SRVClient) MaybeSRV(host string) string {
+func (sc *SRVClient) MaybeSRV(host string) string {
+	return sc.MaybeSRVContext(context.Background(), host)
+}
+
+// MaybeSRVContext calls the MaybeSRVContext method on the DefaultSRVClient
+func MaybeSRVContext(ctx context.Context, host string) string {
+	return DefaultSRVClient.MaybeSRVContext(ctx, host)
+}
+
+// MaybeSRVContext behaves the same as MaybeSRV, but the given Context is
+// threaded through to the underlying DNS exchange
+func (sc *SRVClient) MaybeSRVContext(ctx context.Context, host string) string {
 	if _, p, _ := net.SplitHostPort(host); p == "" {
-		if addr, err := SRV(host); err == nil {
+		if addr, err := sc.SRVContext(ctx, host); err == nil {
 			host = addr
 		}
 	}
 	return host
 }
 
+// pickSRV picks a single weighted-random target among those at the lowest
+// priority seen in srvs.
+//
+// Deprecated: this only ever considers the lowest-priority tier and so
+// doesn't implement RFC 2782's cross-priority failover. Prefer AllSRV (or
+// Dial, for automatic failover) which returns/walks the full RFC 2782 order
 func pickSRV(srvs []*dns.SRV) *dns.SRV {
 	randSrc := rand.NewSource(time.Now().UnixNano())
 	rand := rand.New(randSrc)
@@ -0,0 +1,291 @@
+package srvclient
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fakeExchange returns an ExchangeFunc driven by a map of fully-qualified
+// question name -> canned response, for use with SRVClient.Exchange in tests
+// that shouldn't need to talk to a real resolver
+func fakeExchange(byName map[string]*dns.Msg) ExchangeFunc {
+	return func(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+		res, ok := byName[msg.Question[0].Name]
+		if !ok {
+			return new(dns.Msg).SetRcode(msg, dns.RcodeNameError), nil
+		}
+		res.SetReply(msg)
+		return res, nil
+	}
+}
+
+func srvAnswer(fqdn string, priority, weight, port uint16, target string) *dns.Msg {
+	res := new(dns.Msg)
+	res.Answer = append(res.Answer, &dns.SRV{
+		Hdr:      dns.RR_Header{Name: fqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 60},
+		Priority: priority,
+		Weight:   weight,
+		Port:     port,
+		Target:   target,
+	})
+	return res
+}
+
+func TestExchangeFqdnUsesExchangeField(t *testing.T) {
+	fqdn := "_xmpp._tcp.example.com."
+	sc := &SRVClient{Exchange: fakeExchange(map[string]*dns.Msg{
+		fqdn: srvAnswer(fqdn, 0, 0, 5222, "xmpp.example.com."),
+	})}
+
+	res, err := sc.exchangeFqdn(context.Background(), new(dns.Client), []string{"203.0.113.53:53"}, fqdn, false)
+	if err != nil {
+		t.Fatalf("exchangeFqdn: %v", err)
+	}
+	if len(res.Answer) != 1 {
+		t.Fatalf("expected 1 answer, got %d", len(res.Answer))
+	}
+	srv := res.Answer[0].(*dns.SRV)
+	if srv.Target != "xmpp.example.com." || srv.Port != 5222 {
+		t.Errorf("unexpected SRV target/port: %+v", srv)
+	}
+}
+
+func TestExchangeHostnameUsesExchangeField(t *testing.T) {
+	hostname := "myservice.example.com."
+	sc := &SRVClient{Exchange: fakeExchange(map[string]*dns.Msg{
+		hostname: srvAnswer(hostname, 0, 0, 8080, "myservice-0.example.com."),
+	})}
+
+	res, err := sc.exchangeHostname(context.Background(), hostname, false)
+	if err != nil {
+		t.Fatalf("exchangeHostname: %v", err)
+	}
+	if res == nil || len(res.Answer) != 1 {
+		t.Fatalf("expected a non-empty answer, got %+v", res)
+	}
+}
+
+func TestOrderSRVGroupsByPriority(t *testing.T) {
+	ans := []*dns.SRV{
+		{Priority: 1, Weight: 1, Target: "b"},
+		{Priority: 0, Weight: 1, Target: "a"},
+		{Priority: 1, Weight: 1, Target: "c"},
+	}
+
+	ordered := orderSRV(ans)
+	if len(ordered) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(ordered))
+	}
+	if ordered[0].Target != "a" {
+		t.Errorf("expected priority-0 entry first, got %q", ordered[0].Target)
+	}
+	seen := map[string]bool{}
+	for _, s := range ordered {
+		seen[s.Target] = true
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		if !seen[want] {
+			t.Errorf("orderSRV dropped %q", want)
+		}
+	}
+}
+
+func TestSearchFqdns(t *testing.T) {
+	cfg := &dnsConfig{search: []string{"svc.cluster.local.", "cluster.local."}, ndots: 2}
+
+	tests := []struct {
+		hostname string
+		want     []string
+	}{
+		{"myapp.", []string{"myapp."}},
+		{"a.b.c", []string{"a.b.c."}}, // >= ndots dots, absolute form only
+		{"myapp", []string{"myapp.svc.cluster.local.", "myapp.cluster.local.", "myapp."}},
+	}
+
+	for _, tc := range tests {
+		got := searchFqdns(tc.hostname, cfg)
+		if len(got) != len(tc.want) {
+			t.Errorf("searchFqdns(%q): got %v, want %v", tc.hostname, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("searchFqdns(%q)[%d] = %q, want %q", tc.hostname, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestMinTTL(t *testing.T) {
+	res := &dns.Msg{}
+	res.Answer = []dns.RR{
+		&dns.SRV{Hdr: dns.RR_Header{Ttl: 300}},
+		&dns.SRV{Hdr: dns.RR_Header{Ttl: 0}},
+	}
+	if got := minTTL(res); got != 0 {
+		t.Errorf("minTTL with a TTL-0 record = %v, want 0 (TTL 0 must win, not be treated as unset)", got)
+	}
+
+	empty := &dns.Msg{}
+	if got, want := minTTL(empty), 60*time.Second; got != want {
+		t.Errorf("minTTL with no records = %v, want %v", got, want)
+	}
+}
+
+func TestCheckSecureFailsClosedWithoutTrustAnchor(t *testing.T) {
+	res := &dns.Msg{}
+	res.AuthenticatedData = true
+
+	if err := checkSecure(res, "example.com.", true, true, nil); err == nil {
+		t.Fatal("expected ValidateDNSSEC with a nil anchor to fail closed, got nil error")
+	}
+}
+
+func TestCheckSecureRequiresAuthenticatedData(t *testing.T) {
+	res := &dns.Msg{}
+	if err := checkSecure(res, "example.com.", true, false, nil); err == nil {
+		t.Fatal("expected a response missing the AD bit to fail")
+	}
+	if err := checkSecure(res, "example.com.", false, false, nil); err != nil {
+		t.Errorf("checkSecure should be a no-op when secure is false, got %v", err)
+	}
+}
+
+// signSRV generates a fresh DNSKEY and a valid RRSIG over srv, returning the
+// DNSKEY (for use as a TrustAnchor) and the RRSIG, for exercising
+// validateRRSIG's success path
+func signSRV(t *testing.T, srv *dns.SRV) (*dns.DNSKEY, *dns.RRSIG) {
+	t.Helper()
+
+	const zone = "example.com."
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: dns.RSASHA256,
+	}
+	priv, err := key.Generate(1024)
+	if err != nil {
+		t.Fatalf("generating DNSKEY: %v", err)
+	}
+
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: srv.Hdr.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: srv.Hdr.Ttl},
+		TypeCovered: dns.TypeSRV,
+		Algorithm:   dns.RSASHA256,
+		Labels:      uint8(dns.CountLabel(srv.Hdr.Name)),
+		OrigTtl:     srv.Hdr.Ttl,
+		Expiration:  uint32(time.Now().Add(time.Hour).Unix()),
+		Inception:   uint32(time.Now().Add(-time.Hour).Unix()),
+		KeyTag:      key.KeyTag(),
+		SignerName:  zone,
+	}
+	if err := sig.Sign(priv.(crypto.Signer), []dns.RR{srv}); err != nil {
+		t.Fatalf("signing RRSIG: %v", err)
+	}
+	return key, sig
+}
+
+func TestValidateRRSIGSuccess(t *testing.T) {
+	fqdn := "_xmpp._tcp.example.com."
+	srv := &dns.SRV{
+		Hdr:      dns.RR_Header{Name: fqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 3600},
+		Priority: 0, Weight: 0, Port: 5222, Target: "xmpp.example.com.",
+	}
+	key, sig := signSRV(t, srv)
+
+	res := &dns.Msg{}
+	res.Answer = []dns.RR{srv, sig}
+
+	if err := validateRRSIG(res, key); err != nil {
+		t.Fatalf("validateRRSIG: %v", err)
+	}
+}
+
+func TestValidateRRSIGWrongAnchorFails(t *testing.T) {
+	fqdn := "_xmpp._tcp.example.com."
+	srv := &dns.SRV{
+		Hdr:      dns.RR_Header{Name: fqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 3600},
+		Priority: 0, Weight: 0, Port: 5222, Target: "xmpp.example.com.",
+	}
+	_, sig := signSRV(t, srv)
+	otherKey, _ := signSRV(t, srv) // a different, unrelated key
+
+	res := &dns.Msg{}
+	res.Answer = []dns.RR{srv, sig}
+
+	if err := validateRRSIG(res, otherKey); err == nil {
+		t.Fatal("expected validateRRSIG to fail against a key that didn't produce the RRSIG")
+	}
+}
+
+// fakeExchangeErr always fails, simulating every server/search attempt
+// being exhausted without a response
+func fakeExchangeErr(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	return nil, errors.New("simulated transport failure")
+}
+
+func TestBgCacheRefreshStaleServeAndEviction(t *testing.T) {
+	fqdn := "myservice.example.com."
+	goodRes := srvAnswer(fqdn, 0, 0, 8080, "a."+fqdn)
+
+	sc := &SRVClient{Exchange: fakeExchangeErr}
+	sc.BackgroundCacheGraceWindow = 10 * time.Millisecond
+	c := &bgCache{sc: sc, entries: map[string]*bgCacheEntry{}}
+	c.entries[fqdn] = &bgCacheEntry{hostname: fqdn, res: goodRes, expires: time.Now().Add(-time.Second)}
+
+	// First refresh attempt fails (expired entry, exchange always errors):
+	// the stale answer should still be served
+	c.refreshDue()
+
+	res, err := c.lookup(context.Background(), fqdn, false)
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if res != goodRes {
+		t.Fatalf("expected the stale response to still be served, got %+v", res)
+	}
+	if got := c.stats.StaleServes; got != 1 {
+		t.Errorf("StaleServes = %d, want 1", got)
+	}
+	if got := c.stats.RefreshErrors; got != 1 {
+		t.Errorf("RefreshErrors = %d, want 1", got)
+	}
+
+	// Once the grace window has elapsed, the next refresh attempt should
+	// evict the entry entirely
+	time.Sleep(20 * time.Millisecond)
+	c.refreshDue()
+
+	c.mu.RLock()
+	_, stillPresent := c.entries[fqdn]
+	c.mu.RUnlock()
+	if stillPresent {
+		t.Error("expected the entry to be evicted after the grace window elapsed")
+	}
+}
+
+func TestSRVSecureContextEnforcedThroughBackgroundCache(t *testing.T) {
+	fqdn := "myservice.example.com."
+	insecureRes := srvAnswer(fqdn, 0, 0, 8080, "a."+fqdn) // AuthenticatedData left false
+
+	sc := &SRVClient{Exchange: fakeExchange(map[string]*dns.Msg{fqdn: insecureRes})}
+	sc.EnableBackgroundCache(0)
+
+	// A plain (non-secure) lookup populates the cache with the unauthenticated
+	// response
+	if _, err := sc.SRVContext(context.Background(), fqdn); err != nil {
+		t.Fatalf("priming SRVContext: %v", err)
+	}
+
+	// A secure lookup against the now-cached entry must still enforce Secure,
+	// rather than serving the cached response unchecked
+	if _, err := sc.SRVSecureContext(context.Background(), fqdn); err == nil {
+		t.Fatal("expected SRVSecureContext to reject an unauthenticated response served via a background-cache hit")
+	}
+}